@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"sync"
+	"time"
 )
 
+// defaultRequestTimeout bounds how long a single HTTP request to DTR is
+// allowed to take before it's cancelled and retried.
+const defaultRequestTimeout = 30 * time.Second
+
 // Auditor implements logic for checking repository access for every user within
 // DTR.
 //
 // There are two types of accounts to check for whilst auditing:
-//  - User account repositories
-//  - Organization (org) account repositories
+//   - User account repositories
+//   - Organization (org) account repositories
 //
 // User accounts
 // =============
@@ -40,27 +47,93 @@ type Auditor struct {
 	// time.  Instead, we just do it at the end when creating the report.
 	publicRepos []Repo
 
+	// mu guards Users, Orgs and publicRepos from concurrent writes while
+	// orgs and teams are audited in parallel.
+	mu sync.Mutex
+
+	// concurrency is the maximum number of orgs or teams audited at once.
+	concurrency int
+	// limiter throttles outbound API requests to a fixed rate; nil
+	// disables rate limiting.
+	limiter *rateLimiter
+
+	// checkpoint records which orgs/teams have been audited so Run can
+	// resume a prior, interrupted audit instead of starting from scratch.
+	// Set by main() before calling Run(); a zero-value checkpoint (the
+	// default when unset) checkpoints nothing.
+	checkpoint *checkpoint
+
 	authHeader string
 	host       string
 }
 
-func NewAuditor(host, user, pass string) *Auditor {
+// NewAuditor builds an Auditor that hits host with the given credentials,
+// auditing at most concurrency orgs/teams at a time and issuing no more
+// than qps requests per second. A concurrency below 1 is treated as 1; a
+// qps of 0 or less disables rate limiting.
+func NewAuditor(host, user, pass string, concurrency int, qps float64) *Auditor {
 	encoded := base64.StdEncoding.EncodeToString(
 		[]byte(fmt.Sprintf("%s:%s", user, pass)),
 	)
 
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// an empty-path checkpoint checkpoints nothing; callers that want
+	// resumable audits replace this with one loaded from disk via
+	// loadCheckpoint before calling Run.
+	noopCheckpoint, _ := loadCheckpoint("", false)
+
 	return &Auditor{
-		Users:      map[string]*User{},
-		Orgs:       map[string]*Org{},
-		authHeader: fmt.Sprintf("Basic %s", encoded),
-		host:       host,
+		Users:       map[string]*User{},
+		Orgs:        map[string]*Org{},
+		authHeader:  fmt.Sprintf("Basic %s", encoded),
+		host:        host,
+		concurrency: concurrency,
+		limiter:     newRateLimiter(qps),
+		checkpoint:  noopCheckpoint,
+	}
+}
+
+// runPool calls fn(i) for every i in [0, n), running at most concurrency
+// calls simultaneously, and returns the first error encountered.
+func runPool(concurrency, n int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs <- fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (a *Auditor) Run() error {
+	ctx := context.Background()
+
 	// chain is our pipeline of functions to call which modify internal
 	// audit state in order to produce a full repository access audit
-	chain := []func() error{
+	chain := []func(context.Context) error{
 		// first fetch all repositories, giving us a subset of all users
 		// and organizations which own at least 1 repository. This is
 		// the list of accounts that we need to check
@@ -71,10 +144,15 @@ func (a *Auditor) Run() error {
 		// auditRemainingAccounts fetches accounts that have access to
 		// no repos
 		a.auditRemainingAccounts,
+
+		// applyPublicRepos grants read access to the public repos we
+		// collected above, now that we know which users are restricted
+		// and which orgs are limited-visibility
+		a.applyPublicRepos,
 	}
 
 	for _, f := range chain {
-		if err := f(); err != nil {
+		if err := f(ctx); err != nil {
 			return err
 		}
 	}
@@ -82,9 +160,9 @@ func (a *Auditor) Run() error {
 	return nil
 }
 
-func (a *Auditor) auditAllRepos() error {
+func (a *Auditor) auditAllRepos(ctx context.Context) error {
 	fmt.Println("requesting all repos")
-	repos, err := a.fetchAllRepos()
+	repos, err := a.fetchAllRepos(ctx)
 	if err != nil {
 		return err
 	}
@@ -130,11 +208,39 @@ func (a *Auditor) auditAllRepos() error {
 	return nil
 }
 
-func (a *Auditor) auditOrgs() error {
+// orgTeam pairs an org with one of its teams, letting every team across
+// every org be audited through a single bounded pool in auditOrgs.
+type orgTeam struct {
+	org  string
+	team Team
+}
+
+func (a *Auditor) auditOrgs(ctx context.Context) error {
 	fmt.Println("auditing all organizations; this may take a while...")
-	for orgName, _ := range a.Orgs {
-		// fetch the org's teams
-		teams, err := a.fetchTeamsForOrg(orgName)
+
+	orgNames := make([]string, 0, len(a.Orgs))
+	for orgName := range a.Orgs {
+		orgNames = append(orgNames, orgName)
+	}
+
+	// Stage 1: fetch each org's teams, bounded to a.concurrency orgs at
+	// once. Orgs with no teams have nothing left to audit, so they're
+	// checkpointed as done immediately; everything else is flattened
+	// into a single list so stage 2 doesn't nest a second, independent
+	// pool inside this one (which would let a.concurrency^2 requests run
+	// at once instead of the a.concurrency the flag documents).
+	var mu sync.Mutex
+	var pending []orgTeam
+
+	if err := runPool(a.concurrency, len(orgNames), func(i int) error {
+		orgName := orgNames[i]
+
+		if a.checkpoint.orgDone(orgName) {
+			fmt.Printf(" > skipping org '%s'; already audited by a prior run\n", orgName)
+			return nil
+		}
+
+		teams, err := a.fetchTeamsForOrg(ctx, orgName)
 		if err != nil {
 			return fmt.Errorf(
 				"error fetching teams for org '%s': %s",
@@ -143,36 +249,77 @@ func (a *Auditor) auditOrgs() error {
 			)
 		}
 
-		// i dislike nested fors
+		if len(teams) == 0 {
+			return a.checkpoint.markOrg(orgName)
+		}
+
+		mu.Lock()
 		for _, team := range teams {
-			if err := a.auditTeam(orgName, team); err != nil {
-				return fmt.Errorf(
-					"error auditing teams for org '%s': %s",
-					orgName,
-					err,
-				)
-			}
+			pending = append(pending, orgTeam{org: orgName, team: team})
 		}
+		mu.Unlock()
+		return nil
+	}); err != nil {
+		return err
 	}
-	return nil
+
+	// teamsLeft tracks how many of an org's teams are still outstanding,
+	// so we only checkpoint the org once every one of its teams is done.
+	teamsLeft := map[string]int{}
+	for _, ot := range pending {
+		teamsLeft[ot.org]++
+	}
+
+	// Stage 2: audit every pending team, bounded to a.concurrency teams
+	// at once across all orgs combined.
+	return runPool(a.concurrency, len(pending), func(i int) error {
+		ot := pending[i]
+
+		if err := a.auditTeam(ctx, ot.org, ot.team); err != nil {
+			return fmt.Errorf(
+				"error auditing teams for org '%s': %s",
+				ot.org,
+				err,
+			)
+		}
+
+		mu.Lock()
+		teamsLeft[ot.org]--
+		done := teamsLeft[ot.org] == 0
+		mu.Unlock()
+		if !done {
+			return nil
+		}
+		return a.checkpoint.markOrg(ot.org)
+	})
 }
 
-func (a *Auditor) auditTeam(orgName string, team Team) error {
+func (a *Auditor) auditTeam(ctx context.Context, orgName string, team Team) error {
+	if a.checkpoint.teamDone(orgName, team.Name) {
+		fmt.Printf(" > skipping team '%s/%s'; already audited by a prior run\n", orgName, team.Name)
+		return nil
+	}
+
 	// get all repos and members for this team, and assign each user the
 	// repo permissions granted by the team.
-	repos, err := a.fetchReposForTeam(orgName, team.Name)
+	repos, err := a.fetchReposForTeam(ctx, orgName, team.Name)
 	if err != nil {
 		return err
 	}
-	users, err := a.fetchMembersForTeam(orgName, team.Name)
+	users, err := a.fetchMembersForTeam(ctx, orgName, team.Name)
 	if err != nil {
 		return err
 	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	// add each repo to each user. nested loops...
 	for _, repo := range repos {
-		// set the inner repo struct's Level from the team's
-		// Level..
+		// set the inner repo struct's Level and per-unit access from
+		// the team's top-level fields..
 		repo.Repo.Level = repo.Level
+		repo.Repo.Units = repo.Units
 		for _, u := range users {
 			user, ok := a.Users[u.User.Name]
 			// Note: this user may have no repos, therefore wouldn't
@@ -186,13 +333,14 @@ func (a *Auditor) auditTeam(orgName string, team Team) error {
 			user.AddRepo(repo.Repo)
 		}
 	}
-	return nil
+
+	return a.checkpoint.markTeam(orgName, team.Name)
 }
 
 // auditAccounts pulls all users and organizations from the API
-func (a *Auditor) auditRemainingAccounts() error {
+func (a *Auditor) auditRemainingAccounts(ctx context.Context) error {
 	fmt.Println("fetching remaining accounts")
-	accts, err := a.fetchAccounts()
+	accts, err := a.fetchAccounts(ctx)
 	if err != nil {
 		return err
 	}
@@ -200,25 +348,58 @@ func (a *Auditor) auditRemainingAccounts() error {
 	// Add each org or user to our internal auditor state
 	for _, acc := range accts {
 		if acc.IsOrg {
-			if _, ok := a.Orgs[acc.Name]; !ok {
+			if org, ok := a.Orgs[acc.Name]; !ok {
 				a.Orgs[acc.Name] = &Org{
-					ID:   acc.ID,
-					Name: acc.Name,
+					ID:         acc.ID,
+					Name:       acc.Name,
+					Visibility: acc.Visibility,
 				}
+			} else {
+				org.ID = acc.ID
+				org.Visibility = acc.Visibility
 			}
 		} else {
 			if _, ok := a.Users[acc.Name]; !ok {
 				fmt.Println(" > found new user with no repos")
 				a.Users[acc.Name] = &User{
-					ID:      acc.ID,
-					Name:    acc.Name,
-					IsAdmin: acc.IsAdmin,
+					ID:           acc.ID,
+					Name:         acc.Name,
+					IsAdmin:      acc.IsAdmin,
+					IsRestricted: acc.IsRestricted,
+					Repos:        map[string]Repo{},
 				}
 			} else {
 				a.Users[acc.Name].IsAdmin = acc.IsAdmin
+				a.Users[acc.Name].IsRestricted = acc.IsRestricted
 				a.Users[acc.Name].ID = acc.ID
 			}
 		}
 	}
 	return nil
 }
+
+// applyPublicRepos grants every non-restricted user read access to the
+// public repos gathered in auditAllRepos. Restricted users never gain
+// access this way - they only see repos surfaced via explicit team
+// membership in auditTeam. Repos owned by a limited-visibility org are
+// never blanket-added either, even for non-restricted users; a limited org
+// only exposes repos to its team members.
+func (a *Auditor) applyPublicRepos(ctx context.Context) error {
+	fmt.Println("applying public repo access")
+	for _, repo := range a.publicRepos {
+		if repo.AccountType == AccountTypeOrg {
+			if org, ok := a.Orgs[repo.AccountName]; ok && org.Visibility == VisibilityLimited {
+				continue
+			}
+		}
+
+		repo.Level = Read
+		for _, user := range a.Users {
+			if user.IsRestricted {
+				continue
+			}
+			user.AddRepo(repo)
+		}
+	}
+	return nil
+}
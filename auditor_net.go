@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,8 +12,9 @@ func (a *Auditor) url(endpoint string) string {
 	return fmt.Sprintf("%s/%s", a.host, endpoint)
 }
 
-func (a *Auditor) get(endpoint string) (*http.Response, error) {
-	req, err := http.NewRequest(
+func (a *Auditor) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		a.url(endpoint),
 		nil,
@@ -20,56 +22,65 @@ func (a *Auditor) get(endpoint string) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %s", err)
 	}
-	resp, err := a.do(req)
+	resp, err := a.do(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err)
 	}
 	return resp, nil
 }
 
-func (a *Auditor) fetchAllRepos() ([]Repo, error) {
-	resp, err := a.get("api/v0/repositories?limit=999999")
-	if err != nil {
-		return nil, fmt.Errorf("error requesting all repos: %s", err)
-	}
-	defer resp.Body.Close()
+func (a *Auditor) fetchAllRepos(ctx context.Context) ([]Repo, error) {
+	items, errs := a.paginate(ctx, "api/v0/repositories", "repositories")
 
-	aux := RepoWrapper{}
-	if err := json.NewDecoder(resp.Body).Decode(&aux); err != nil {
-		return nil, fmt.Errorf("error decoding accounts: %s", err)
+	var repos []Repo
+	for raw := range items {
+		var r Repo
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("error decoding repo: %s", err)
+		}
+		repos = append(repos, r)
 	}
-	return aux.Repos, nil
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("error requesting all repos: %s", err)
+	}
+	return repos, nil
 }
 
 // fetchAccounts pulls all users and organizations from the API
-func (a *Auditor) fetchAccounts() ([]Account, error) {
+func (a *Auditor) fetchAccounts(ctx context.Context) ([]Account, error) {
 	fmt.Println("fetching accounts")
 
-	// Make a request to fetch all accounts
-	resp, err := a.get("enzi/v0/accounts")
-	if err != nil {
-		return nil, fmt.Errorf("error requesting accounts: %s", err)
+	items, errs := a.paginate(ctx, "enzi/v0/accounts", "accounts")
+
+	var accounts []Account
+	for raw := range items {
+		var acc Account
+		if err := json.Unmarshal(raw, &acc); err != nil {
+			return nil, fmt.Errorf("error decoding account: %s", err)
+		}
+		accounts = append(accounts, acc)
 	}
-	defer resp.Body.Close()
-
-	// Note that the API response for the list of accounts is wrapped in
-	// an object.
-	aux := struct {
-		Accounts []Account `json:"accounts"`
-	}{}
-	if err := json.NewDecoder(resp.Body).Decode(&aux); err != nil {
-		return nil, fmt.Errorf("error decoding accounts: %s", err)
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("error requesting accounts: %s", err)
 	}
-
-	return aux.Accounts, nil
+	return accounts, nil
 }
 
 // do adds authorization headers to an http.Request, makes the request and
 // returns the response.
 //
-// This uses retry defined in util.go to attempt the http request up to 3 times
-// before finally packing up and going home to its fam.
-func (a *Auditor) do(req *http.Request) (*http.Response, error) {
+// This applies the rate limiter and a per-request timeout derived from ctx,
+// then uses retry defined in util.go to attempt the http request up to 3
+// times before finally packing up and going home to its fam.
+func (a *Auditor) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := a.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
 	req.Header.Set("Authorization", a.authHeader)
 
 	// create a response which is caught inside the closure below which
@@ -104,51 +115,63 @@ func (a *Auditor) do(req *http.Request) (*http.Response, error) {
 
 // fetchTeamsForOrg returns all repositories owned by a user or
 // organization
-func (a *Auditor) fetchTeamsForOrg(name string) ([]Team, error) {
-	resp, err := a.get(fmt.Sprintf("enzi/v0/accounts/%s/teams?limit=5000", name))
-	if err != nil {
-		return nil, fmt.Errorf("error requesting teams: %s", err)
+func (a *Auditor) fetchTeamsForOrg(ctx context.Context, name string) ([]Team, error) {
+	items, errs := a.paginate(ctx, fmt.Sprintf("enzi/v0/accounts/%s/teams", name), "teams")
+
+	var teams []Team
+	for raw := range items {
+		var t Team
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, fmt.Errorf("error decoding team: %s", err)
+		}
+		teams = append(teams, t)
 	}
-	defer resp.Body.Close()
-	aux := TeamWrapper{}
-	if err := json.NewDecoder(resp.Body).Decode(&aux); err != nil {
-		return nil, fmt.Errorf("error decoding teams: %s", err)
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("error requesting teams: %s", err)
 	}
-	return aux.Teams, nil
+	return teams, nil
 }
 
 // fetchReposForTeam returns all repositories a team has access to
 // organization
-func (a *Auditor) fetchReposForTeam(orgName, teamName string) ([]TeamRepo, error) {
-	resp, err := a.get(fmt.Sprintf(
-		"api/v0/accounts/%s/teams/%s/repositoryAccess?limit=100000",
+func (a *Auditor) fetchReposForTeam(ctx context.Context, orgName, teamName string) ([]TeamRepo, error) {
+	items, errs := a.paginate(ctx, fmt.Sprintf(
+		"api/v0/accounts/%s/teams/%s/repositoryAccess",
 		orgName,
 		teamName,
-	))
-	if err != nil {
-		return nil, fmt.Errorf("error requesting repos for team: %s", err)
+	), "repositoryAccessList")
+
+	var repos []TeamRepo
+	for raw := range items {
+		var r TeamRepo
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("error decoding team repo: %s", err)
+		}
+		repos = append(repos, r)
 	}
-	defer resp.Body.Close()
-	aux := TeamRepoWrapper{}
-	if err := json.NewDecoder(resp.Body).Decode(&aux); err != nil {
-		return nil, fmt.Errorf("error decoding repos for team: %s", err)
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("error requesting repos for team: %s", err)
 	}
-	return aux.Repos, nil
+	return repos, nil
 }
 
-func (a *Auditor) fetchMembersForTeam(orgName, teamName string) ([]TeamMember, error) {
-	resp, err := a.get(fmt.Sprintf(
-		"enzi/v0/accounts/%s/teams/%s/members?limit=10000",
+func (a *Auditor) fetchMembersForTeam(ctx context.Context, orgName, teamName string) ([]TeamMember, error) {
+	items, errs := a.paginate(ctx, fmt.Sprintf(
+		"enzi/v0/accounts/%s/teams/%s/members",
 		orgName,
 		teamName,
-	))
-	if err != nil {
-		return nil, fmt.Errorf("error requesting users for team: %s", err)
+	), "members")
+
+	var members []TeamMember
+	for raw := range items {
+		var m TeamMember
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("error decoding team member: %s", err)
+		}
+		members = append(members, m)
 	}
-	defer resp.Body.Close()
-	aux := TeamMemberWrapper{}
-	if err := json.NewDecoder(resp.Body).Decode(&aux); err != nil {
-		return nil, fmt.Errorf("error decoding users for team: %s", err)
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("error requesting users for team: %s", err)
 	}
-	return aux.Members, nil
+	return members, nil
 }
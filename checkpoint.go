@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkpointTTL is how long a checkpoint file remains valid. A checkpoint
+// older than this is treated as stale and discarded, so a long-abandoned
+// partial audit doesn't silently mask real access drift on the next run.
+const checkpointTTL = 6 * time.Hour
+
+// checkpointEntry records a single unit of completed work - an audited org,
+// or an audited team within an org. Entries are appended to the checkpoint
+// file as JSON lines, so a crash mid-audit only loses the entry being
+// written, not the whole file.
+type checkpointEntry struct {
+	Time time.Time `json:"time"`
+	Org  string    `json:"org"`
+	Team string    `json:"team,omitempty"`
+}
+
+// checkpoint tracks which orgs and teams have already been audited, so a
+// restarted Run can skip work a prior run already completed.
+//
+// orgDone/teamDone/markOrg/markTeam are called concurrently from the
+// -concurrency worker pool in auditOrgs/auditTeam, so mu guards both the
+// in-memory maps and writes to the on-disk file.
+type checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+
+	orgsDone  map[string]bool
+	teamsDone map[string]bool // keyed by "org/team"
+}
+
+// loadCheckpoint reads path, if it exists, and returns a checkpoint
+// recording the orgs/teams it says are already audited. An empty path
+// disables checkpointing entirely. force ignores any existing file and
+// starts fresh; a checkpoint older than checkpointTTL is also discarded.
+func loadCheckpoint(path string, force bool) (*checkpoint, error) {
+	c := &checkpoint{
+		orgsDone:  map[string]bool{},
+		teamsDone: map[string]bool{},
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	if !force {
+		if err := c.load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checkpoint: %s", err)
+	}
+	c.file = f
+	return c, nil
+}
+
+// load reads existing checkpoint entries from path. Each entry's staleness
+// is judged independently - an entry older than checkpointTTL is dropped,
+// but that alone doesn't invalidate other, fresher entries appended to the
+// same file later.
+func (c *checkpoint) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening checkpoint: %s", err)
+	}
+	defer f.Close()
+
+	var skipped int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if time.Since(e.Time) > checkpointTTL {
+			skipped++
+			continue
+		}
+		if e.Team == "" {
+			c.orgsDone[e.Org] = true
+		} else {
+			c.teamsDone[e.Org+"/"+e.Team] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading checkpoint: %s", err)
+	}
+
+	if skipped > 0 {
+		fmt.Printf(" > ignored %d checkpoint entries older than %s\n", skipped, checkpointTTL)
+	}
+	return nil
+}
+
+// orgDone reports whether org was fully audited by a prior run.
+func (c *checkpoint) orgDone(org string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.orgsDone[org]
+}
+
+// teamDone reports whether team within org was audited by a prior run.
+func (c *checkpoint) teamDone(org, team string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.teamsDone[org+"/"+team]
+}
+
+// markOrg records that org's teams have all been audited.
+func (c *checkpoint) markOrg(org string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.orgsDone[org] = true
+	return c.append(checkpointEntry{Time: time.Now(), Org: org})
+}
+
+// markTeam records that team within org has been audited.
+func (c *checkpoint) markTeam(org, team string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.teamsDone[org+"/"+team] = true
+	return c.append(checkpointEntry{Time: time.Now(), Org: org, Team: team})
+}
+
+func (c *checkpoint) append(e checkpointEntry) error {
+	if c.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the underlying checkpoint file, if one is open.
+func (c *checkpoint) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
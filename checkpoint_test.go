@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckpointResumesFromExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	cp, err := loadCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("error loading checkpoint: %s", err)
+	}
+	if err := cp.markOrg("org1"); err != nil {
+		t.Fatalf("error marking org done: %s", err)
+	}
+	if err := cp.markTeam("org2", "team1"); err != nil {
+		t.Fatalf("error marking team done: %s", err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatalf("error closing checkpoint: %s", err)
+	}
+
+	resumed, err := loadCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("error resuming checkpoint: %s", err)
+	}
+	defer resumed.Close()
+
+	if !resumed.orgDone("org1") {
+		t.Fatalf("expected org1 to be marked done after resume")
+	}
+	if !resumed.teamDone("org2", "team1") {
+		t.Fatalf("expected org2/team1 to be marked done after resume")
+	}
+	if resumed.orgDone("org3") {
+		t.Fatalf("org3 was never marked done")
+	}
+}
+
+func TestCheckpointForceIgnoresExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	cp, err := loadCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("error loading checkpoint: %s", err)
+	}
+	if err := cp.markOrg("org1"); err != nil {
+		t.Fatalf("error marking org done: %s", err)
+	}
+	cp.Close()
+
+	forced, err := loadCheckpoint(path, true)
+	if err != nil {
+		t.Fatalf("error loading checkpoint with force: %s", err)
+	}
+	defer forced.Close()
+
+	if forced.orgDone("org1") {
+		t.Fatalf("force should ignore the existing checkpoint")
+	}
+}
+
+func TestCheckpointLoadKeepsFreshEntriesAfterAStaleOne(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	stale, err := json.Marshal(checkpointEntry{Time: time.Now().Add(-2 * checkpointTTL), Org: "old-org"})
+	if err != nil {
+		t.Fatalf("error marshalling stale entry: %s", err)
+	}
+	fresh, err := json.Marshal(checkpointEntry{Time: time.Now(), Org: "new-org"})
+	if err != nil {
+		t.Fatalf("error marshalling fresh entry: %s", err)
+	}
+	data := append(append(stale, '\n'), append(fresh, '\n')...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("error writing checkpoint fixture: %s", err)
+	}
+
+	cp, err := loadCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("error loading checkpoint: %s", err)
+	}
+	defer cp.Close()
+
+	if cp.orgDone("old-org") {
+		t.Fatalf("old-org is older than checkpointTTL and should have been dropped")
+	}
+	if !cp.orgDone("new-org") {
+		t.Fatalf("new-org is fresh and should have survived a stale entry earlier in the file")
+	}
+}
+
+// TestCheckpointConcurrentAccess exercises orgDone/teamDone/markOrg/markTeam
+// from many goroutines at once, matching how the -concurrency worker pool in
+// auditOrgs/auditTeam calls them. Run with -race to catch unguarded access.
+func TestCheckpointConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	cp, err := loadCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("error loading checkpoint: %s", err)
+	}
+	defer cp.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			org := fmt.Sprintf("org%d", i)
+			team := fmt.Sprintf("team%d", i)
+			cp.orgDone(org)
+			cp.teamDone(org, team)
+			if err := cp.markOrg(org); err != nil {
+				t.Errorf("error marking org done: %s", err)
+			}
+			if err := cp.markTeam(org, team); err != nil {
+				t.Errorf("error marking team done: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		org := fmt.Sprintf("org%d", i)
+		team := fmt.Sprintf("team%d", i)
+		if !cp.orgDone(org) {
+			t.Fatalf("%s was not marked done", org)
+		}
+		if !cp.teamDone(org, team) {
+			t.Fatalf("%s/%s was not marked done", org, team)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"crypto/tls"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -26,17 +26,52 @@ func init() {
 }
 
 func main() {
+	format := flag.String("format", "json", "report format: json, csv, html or diff")
+	output := flag.String("output", "", "file to write the report to (defaults to stdout)")
+	prior := flag.String("prior", "", "path to a prior JSON report; required when -format=diff")
+	concurrency := flag.Int("concurrency", 8, "number of orgs/teams to audit concurrently")
+	qps := flag.Float64("qps", 10, "maximum number of requests per second to issue against DTR; 0 disables limiting")
+	checkpointPath := flag.String("checkpoint", "", "path to a checkpoint file; if set, resumes an interrupted audit and is updated as the audit progresses")
+	force := flag.Bool("force", false, "ignore any existing checkpoint and audit everything from scratch")
+	flag.Parse()
+
 	host := os.Getenv("HOST")
 	user := os.Getenv("USER")
 	pass := os.Getenv("PASS")
 
-	auditor := NewAuditor(host, user, pass)
-	err := auditor.Run()
+	reporter, err := NewReporter(*format, *prior)
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	cp, err := loadCheckpoint(*checkpointPath, *force)
 	if err != nil {
 		fmt.Printf("ERROR: %s\n", err)
 		os.Exit(1)
 	}
+	defer cp.Close()
 
-	data, _ := json.MarshalIndent(auditor, "", "  ")
-	fmt.Printf("%s\n", data)
+	auditor := NewAuditor(host, user, pass, *concurrency, *qps)
+	auditor.checkpoint = cp
+	if err := auditor.Run(); err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := reporter.Report(out, auditor); err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// defaultPageSize is the number of items requested per page when paginating
+// a DTR list endpoint.
+const defaultPageSize = 100
+
+// paginate streams every item from a paginated DTR list endpoint. endpoint
+// is the relative API path (no query string); itemsKey is the JSON field
+// in each page's envelope holding that page's items. Pages are followed via
+// the "nextPageStart" cursor DTR embeds in each envelope.
+//
+// Items are decoded as json.RawMessage and sent to the returned channel as
+// each page arrives, so callers can start processing before the whole list
+// has been fetched. Both channels are closed once pagination finishes, ctx
+// is cancelled, or an error occurs - check the error channel after the
+// items channel closes. A page request that fails with a transient error
+// (eg. a 5xx) is retried by get()/do() without re-requesting pages that
+// have already been sent down the channel.
+func (a *Auditor) paginate(ctx context.Context, endpoint, itemsKey string) (<-chan json.RawMessage, <-chan error) {
+	items := make(chan json.RawMessage)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(items)
+		defer close(errs)
+
+		pageStart := ""
+		for {
+			page := fmt.Sprintf("%s?limit=%d", endpoint, defaultPageSize)
+			if pageStart != "" {
+				page = fmt.Sprintf("%s&pageStart=%s", page, url.QueryEscape(pageStart))
+			}
+
+			resp, err := a.get(ctx, page)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				errs <- fmt.Errorf("error reading page body: %s", err)
+				return
+			}
+
+			var envelope map[string]json.RawMessage
+			if err := json.Unmarshal(body, &envelope); err != nil {
+				errs <- fmt.Errorf("error decoding page: %s", err)
+				return
+			}
+
+			var pageItems []json.RawMessage
+			if raw, ok := envelope[itemsKey]; ok {
+				if err := json.Unmarshal(raw, &pageItems); err != nil {
+					errs <- fmt.Errorf("error decoding page items: %s", err)
+					return
+				}
+			}
+
+			for _, item := range pageItems {
+				select {
+				case items <- item:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+
+			var next string
+			if raw, ok := envelope["nextPageStart"]; ok {
+				if err := json.Unmarshal(raw, &next); err != nil {
+					errs <- fmt.Errorf("error decoding next page cursor: %s", err)
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			pageStart = next
+		}
+	}()
+
+	return items, errs
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestAuditor(host string) *Auditor {
+	a := NewAuditor(host, "user", "pass", 1, 0)
+	return a
+}
+
+// TestPaginateAssemblesMultiplePages asserts that paginate follows
+// nextPageStart across pages and yields every item exactly once.
+func TestPaginateAssemblesMultiplePages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/things", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageStart") == "" {
+			fmt.Fprint(w, `{"things": ["a", "b"], "nextPageStart": "page2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"things": ["c"]}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := newTestAuditor(srv.URL)
+	items, errs := a.paginate(context.Background(), "things", "things")
+
+	var got []string
+	for raw := range items {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			t.Fatalf("error decoding item: %s", err)
+		}
+		got = append(got, s)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v items, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v items, want %v", got, want)
+		}
+	}
+}
+
+// TestPaginateRetriesTransientErrorWithoutDuplicatingPages asserts that a
+// mid-stream 5xx is retried without re-sending pages that were already
+// consumed.
+func TestPaginateRetriesTransientErrorWithoutDuplicatingPages(t *testing.T) {
+	var page1Requests, page2Requests int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/things", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageStart") == "" {
+			page1Requests++
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"things": ["a", "b"], "nextPageStart": "page2"}`)
+			return
+		}
+
+		page2Requests++
+		if page2Requests == 1 {
+			// simulate a transient failure on the first attempt at page 2
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"things": ["c"]}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := newTestAuditor(srv.URL)
+	items, errs := a.paginate(context.Background(), "things", "things")
+
+	var got []string
+	for raw := range items {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			t.Fatalf("error decoding item: %s", err)
+		}
+		got = append(got, s)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v items, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v items, want %v", got, want)
+		}
+	}
+
+	if page1Requests != 1 {
+		t.Fatalf("page 1 was requested %d times, want exactly once", page1Requests)
+	}
+	if page2Requests != 2 {
+		t.Fatalf("page 2 was requested %d times, want exactly 2 (1 failure + 1 retry)", page2Requests)
+	}
+}
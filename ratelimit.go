@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// rateLimiter is a simple token-bucket limiter used to cap the number of
+// HTTP requests the auditor issues against DTR per second, so we don't
+// hammer the API on tenants with hundreds of orgs.
+type rateLimiter struct {
+	tokens chan struct{}
+	ticker *time.Ticker
+}
+
+// newRateLimiter returns a rateLimiter permitting qps requests per second.
+// A qps of 0 or less disables limiting entirely.
+func newRateLimiter(qps float64) *rateLimiter {
+	if qps <= 0 {
+		return nil
+	}
+
+	r := &rateLimiter{
+		tokens: make(chan struct{}, 1),
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / qps)),
+	}
+
+	go func() {
+		for range r.ticker.C {
+			select {
+			case r.tokens <- struct{}{}:
+			default:
+				// a token is already waiting to be spent; drop this tick
+			}
+		}
+	}()
+
+	return r
+}
+
+// wait blocks until a token is available or ctx is cancelled. A nil
+// rateLimiter (limiting disabled) always returns immediately.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	select {
+	case <-r.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+)
+
+// Reporter writes an Auditor's audited state to w in some output format.
+type Reporter interface {
+	Report(w io.Writer, a *Auditor) error
+}
+
+// NewReporter returns the Reporter for the given format ("json", "csv",
+// "html" or "diff"). priorPath is the path to a previously-written JSON
+// report and is only required (and only used) for the "diff" format.
+func NewReporter(format, priorPath string) (Reporter, error) {
+	switch format {
+	case "", "json":
+		return jsonReporter{}, nil
+	case "csv":
+		return csvReporter{}, nil
+	case "html":
+		return htmlReporter{}, nil
+	case "diff":
+		if priorPath == "" {
+			return nil, fmt.Errorf("diff format requires -prior to point at a previous JSON report")
+		}
+		return diffReporter{priorPath: priorPath}, nil
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// jsonReporter writes the Auditor verbatim as indented JSON, matching the
+// output the tool has always produced.
+type jsonReporter struct{}
+
+func (jsonReporter) Report(w io.Writer, a *Auditor) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling report: %s", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// userRepoAccess is a single user/repo/unit/access-level tuple, flattened
+// out of the Auditor's nested maps so it's easy to write one row per tuple
+// for the CSV and HTML reports.
+type userRepoAccess struct {
+	User  string
+	Repo  string
+	Unit  Unit
+	Level Access
+}
+
+// flatten produces a sorted slice of userRepoAccess tuples from the
+// Auditor's state, one per unit per repo, so a user with read on a repo's
+// code but admin on its packages shows up as two distinct rows rather than
+// collapsing into one misleading overall number.
+func flatten(a *Auditor) []userRepoAccess {
+	var rows []userRepoAccess
+	for _, user := range a.Users {
+		for _, repo := range user.Repos {
+			for _, unit := range units {
+				rows = append(rows, userRepoAccess{
+					User:  user.Name,
+					Repo:  repo.Name,
+					Unit:  unit,
+					Level: repo.unitLevel(unit),
+				})
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].User != rows[j].User {
+			return rows[i].User < rows[j].User
+		}
+		if rows[i].Repo != rows[j].Repo {
+			return rows[i].Repo < rows[j].Repo
+		}
+		return rows[i].Unit < rows[j].Unit
+	})
+	return rows
+}
+
+// csvReporter writes one row per user/repo/unit/access-level tuple.
+type csvReporter struct{}
+
+func (csvReporter) Report(w io.Writer, a *Auditor) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"user", "repo", "unit", "access"}); err != nil {
+		return err
+	}
+	for _, row := range flatten(a) {
+		if err := cw.Write([]string{row.User, row.Repo, string(row.Unit), row.Level.String()}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// htmlReport is the data handed to the HTML report template.
+type htmlReport struct {
+	Rows []userRepoAccess
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DTR access audit</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { cursor: pointer; background: #eee; }
+</style>
+<script>
+function sortTable(table, col) {
+	var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+	var asc = table.getAttribute("data-sort-col") != col;
+	rows.sort(function(a, b) {
+		var x = a.cells[col].innerText, y = b.cells[col].innerText;
+		return asc ? x.localeCompare(y) : y.localeCompare(x);
+	});
+	rows.forEach(function(r) { table.tBodies[0].appendChild(r); });
+	table.setAttribute("data-sort-col", asc ? col : -1);
+}
+function makeSortable(table) {
+	Array.prototype.forEach.call(table.tHead.rows[0].cells, function(th, i) {
+		th.addEventListener("click", function() { sortTable(table, i); });
+	});
+}
+window.onload = function() {
+	Array.prototype.forEach.call(document.querySelectorAll("table"), makeSortable);
+};
+</script>
+</head>
+<body>
+<h1>DTR access audit</h1>
+
+<h2>By user</h2>
+<table>
+<thead><tr><th>User</th><th>Repo</th><th>Unit</th><th>Access</th></tr></thead>
+<tbody>
+{{range .Rows}}
+<tr><td>{{.User}}</td><td>{{.Repo}}</td><td>{{.Unit}}</td><td>{{.Level}}</td></tr>
+{{end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// htmlReporter writes a single HTML page with a sortable table of every
+// user/repo/unit/access-level tuple.
+type htmlReporter struct{}
+
+func (htmlReporter) Report(w io.Writer, a *Auditor) error {
+	return htmlReportTemplate.Execute(w, htmlReport{
+		Rows: flatten(a),
+	})
+}
+
+// accessDiff describes how a single user's access to one unit of a repo
+// changed between two audits.
+type accessDiff struct {
+	User   string
+	Repo   string
+	Unit   Unit
+	Before Access
+	After  Access
+}
+
+// diffReporter compares the current Auditor against a prior JSON report and
+// emits the accesses that were added or removed between the two.
+type diffReporter struct {
+	priorPath string
+}
+
+func (d diffReporter) Report(w io.Writer, a *Auditor) error {
+	prior, err := LoadAuditor(d.priorPath)
+	if err != nil {
+		return fmt.Errorf("error loading prior report: %s", err)
+	}
+
+	var added, removed []accessDiff
+	for _, user := range a.Users {
+		priorUser, ok := prior.Users[user.Name]
+		for name, repo := range user.Repos {
+			var priorRepo Repo
+			if ok {
+				priorRepo = priorUser.Repos[name]
+			}
+			for _, unit := range units {
+				before, after := priorRepo.unitLevel(unit), repo.unitLevel(unit)
+				if before != after {
+					added = append(added, accessDiff{User: user.Name, Repo: name, Unit: unit, Before: before, After: after})
+				}
+			}
+		}
+	}
+	// removed only covers repos that vanished from the user's access
+	// entirely; a repo that's still present but dropped a unit to None
+	// already shows up above as a ~ transition in added.
+	for _, priorUser := range prior.Users {
+		user, ok := a.Users[priorUser.Name]
+		for name, repo := range priorUser.Repos {
+			if ok {
+				if _, stillHasAccess := user.Repos[name]; stillHasAccess {
+					continue
+				}
+			}
+			for _, unit := range units {
+				if before := repo.unitLevel(unit); before != None {
+					removed = append(removed, accessDiff{User: priorUser.Name, Repo: name, Unit: unit, Before: before})
+				}
+			}
+		}
+	}
+
+	sortDiffs := func(diffs []accessDiff) {
+		sort.Slice(diffs, func(i, j int) bool {
+			if diffs[i].User != diffs[j].User {
+				return diffs[i].User < diffs[j].User
+			}
+			if diffs[i].Repo != diffs[j].Repo {
+				return diffs[i].Repo < diffs[j].Repo
+			}
+			return diffs[i].Unit < diffs[j].Unit
+		})
+	}
+	sortDiffs(added)
+	sortDiffs(removed)
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	fmt.Fprintf(bw, "added:\n")
+	for _, d := range added {
+		if d.Before == None {
+			fmt.Fprintf(bw, "  + %s now has %s on %s (%s)\n", d.User, d.After, d.Repo, d.Unit)
+		} else {
+			fmt.Fprintf(bw, "  ~ %s went from %s to %s on %s (%s)\n", d.User, d.Before, d.After, d.Repo, d.Unit)
+		}
+	}
+	fmt.Fprintf(bw, "removed:\n")
+	for _, d := range removed {
+		fmt.Fprintf(bw, "  - %s lost %s on %s (%s)\n", d.User, d.Before, d.Repo, d.Unit)
+	}
+	return nil
+}
+
+// LoadAuditor reads a previously-written JSON report from path and returns
+// the Auditor it describes, so it can be used as the baseline for a diff
+// report.
+func LoadAuditor(path string) (*Auditor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening prior report: %s", err)
+	}
+	defer f.Close()
+
+	a := &Auditor{}
+	if err := json.NewDecoder(f).Decode(a); err != nil {
+		return nil, fmt.Errorf("error decoding prior report: %s", err)
+	}
+	return a, nil
+}
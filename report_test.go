@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testAuditor() *Auditor {
+	a := NewAuditor("", "user", "pass", 1, 0)
+	a.Users["alice"] = &User{
+		Name: "alice",
+		Repos: map[string]Repo{
+			"team/repo": {
+				Name:  "team/repo",
+				Level: Read,
+				Units: map[Unit]Access{UnitCode: Read, UnitPackages: Admin},
+			},
+		},
+	}
+	return a
+}
+
+func TestFlattenEmitsOneRowPerUnit(t *testing.T) {
+	rows := flatten(testAuditor())
+
+	if len(rows) != len(units) {
+		t.Fatalf("got %d rows, want %d (one per unit)", len(rows), len(units))
+	}
+	for _, row := range rows {
+		if row.User != "alice" || row.Repo != "team/repo" {
+			t.Fatalf("unexpected row: %+v", row)
+		}
+	}
+
+	var packages userRepoAccess
+	for _, row := range rows {
+		if row.Unit == UnitPackages {
+			packages = row
+		}
+	}
+	if packages.Level != Admin {
+		t.Fatalf("got %s access to packages, want admin", packages.Level)
+	}
+}
+
+func TestCSVReporterWritesPerUnitRows(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (csvReporter{}).Report(&buf, testAuditor()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "alice,team/repo,packages,admin") {
+		t.Fatalf("csv output missing per-unit row, got:\n%s", out)
+	}
+}
+
+func TestHTMLReporterOmitsEmptyOrgTeamTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (htmlReporter{}).Report(&buf, testAuditor()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "By organization") {
+		t.Fatalf("html report should not contain the dead org/team table, got:\n%s", out)
+	}
+	if !strings.Contains(out, "alice") || !strings.Contains(out, "packages") {
+		t.Fatalf("html report missing per-user row, got:\n%s", out)
+	}
+}
+
+func TestDiffReporterDetectsPerUnitEscalation(t *testing.T) {
+	prior := testAuditor()
+	prior.Users["alice"].Repos["team/repo"] = Repo{
+		Name:  "team/repo",
+		Level: Read,
+		Units: map[Unit]Access{UnitCode: Read, UnitPackages: Read},
+	}
+
+	dir := t.TempDir()
+	priorPath := filepath.Join(dir, "prior.json")
+	f, err := os.Create(priorPath)
+	if err != nil {
+		t.Fatalf("error creating prior report: %s", err)
+	}
+	if err := json.NewEncoder(f).Encode(prior); err != nil {
+		t.Fatalf("error encoding prior report: %s", err)
+	}
+	f.Close()
+
+	var buf bytes.Buffer
+	reporter := diffReporter{priorPath: priorPath}
+	if err := reporter.Report(&buf, testAuditor()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "alice went from read to admin on team/repo (packages)") {
+		t.Fatalf("diff output missing per-unit escalation, got:\n%s", out)
+	}
+}
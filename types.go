@@ -6,8 +6,53 @@ const (
 
 	VisibilityPublic  = "public"
 	VisibilityPrivate = "private"
+	VisibilityLimited = "limited"
 )
 
+// Access represents the level of access a user or team has been granted to
+// a repository. Levels are ordered so that comparisons (eg. "is this access
+// greater than the access we already recorded") work directly on the int
+// value.
+type Access int
+
+const (
+	None Access = iota
+	Read
+	ReadWrite
+	Admin
+)
+
+// String returns the human-readable name for an access level, as used in
+// report output.
+func (a Access) String() string {
+	switch a {
+	case Read:
+		return "read"
+	case ReadWrite:
+		return "read-write"
+	case Admin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// Unit is a single capability exposed by a repository. DTR grants team
+// access per-unit rather than as one scalar, so a team can have read on a
+// repo's code but admin on its packages.
+type Unit string
+
+const (
+	UnitCode     Unit = "code"
+	UnitPackages Unit = "packages"
+	UnitHelm     Unit = "helm"
+	UnitWebhooks Unit = "webhooks"
+	UnitPulls    Unit = "pulls"
+)
+
+// units lists every Unit we track, in report display order.
+var units = []Unit{UnitCode, UnitPackages, UnitHelm, UnitWebhooks, UnitPulls}
+
 // Repo represents an individual repository, including access level and
 // visibility (public or private)
 type Repo struct {
@@ -18,14 +63,29 @@ type Repo struct {
 	Level       Access `json:"access"`
 	AccountName string `json:"namespace"`
 	AccountType string `json:"namespaceType"`
+	// Units holds per-unit access, eg. read on code but admin on
+	// packages. It's only populated from DTR's team-unit data (see
+	// TeamRepo); repos we only know an overall Level for (owned or
+	// public repos) leave this nil and fall back to Level for every unit.
+	Units map[Unit]Access `json:"units,omitempty"`
+}
+
+// unitLevel returns the access level r grants for unit, falling back to
+// the repo's overall Level when no per-unit data was collected for it.
+func (r Repo) unitLevel(unit Unit) Access {
+	if lvl, ok := r.Units[unit]; ok {
+		return lvl
+	}
+	return r.Level
 }
 
 // TeamRepo is the type returned when querying for the repositories that a
 // team can access.  Note that the API returns the access level as a top
 // level field - not in the repo. :(
 type TeamRepo struct {
-	Repo  Repo   `json:"repository"`
-	Level Access `json:"accessLevel"`
+	Repo  Repo            `json:"repository"`
+	Level Access          `json:"accessLevel"`
+	Units map[Unit]Access `json:"unitAccess,omitempty"`
 }
 
 // Account represents a single account fetched from the list of accounts
@@ -35,23 +95,33 @@ type Account struct {
 	Name    string `json:"name"`
 	IsOrg   bool   `json:"isOrg"`
 	IsAdmin bool   `json:"isAdmin"`
+	// IsRestricted is true for users DTR marks as "restricted" - these
+	// users don't implicitly gain read access to public repos and only
+	// see what they've been explicitly granted via team membership.
+	IsRestricted bool `json:"isRestricted"`
+	// Visibility only applies to org accounts: "public" orgs expose their
+	// public repos to every user, while "limited" orgs never blanket-grant
+	// access to non-members, even for public repos.
+	Visibility string `json:"visibility"`
 }
 
-// Team represents a team within an organization, including all users which
-// are a member of the team and all repositories it has access to.
+// Team represents a team within an organization. Its repos and members are
+// fetched and merged into each member's User.Repos by auditTeam rather than
+// stored here, since nothing else needs to look them up by team afterwards.
 type Team struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Users []Account
-	Repos []Repo
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 // Org represents an organization within DTR. An org has many repos which are
 // visibile to org admins and to normal users via teams.
 type Org struct {
-	ID    string
-	Name  string
-	Teams []Team
+	ID   string
+	Name string
+	// Visibility is "public", "private" or "limited". A "limited" org
+	// never blanket-exposes its public repos to non-member users; access
+	// must come from explicit team membership instead.
+	Visibility string
 }
 
 // User represents an individual user within DTR with the repositories they
@@ -60,6 +130,10 @@ type User struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
 	IsAdmin bool   `json:"isAdmin"`
+	// IsRestricted mirrors Account.IsRestricted: restricted users never
+	// gain implicit read access to public repos and only see what's been
+	// explicitly granted via team membership.
+	IsRestricted bool `json:"isRestricted"`
 	// Repos is a map of repository names to repositories they have access
 	// to.  It's a map to ensure uniqueness of repos.
 	// Note that this holds **all** repositories the user has access to
@@ -67,30 +141,34 @@ type User struct {
 	Repos map[string]Repo
 }
 
+// AddRepo merges r into the user's known access to that repo, keeping the
+// highest access level granted for each unit rather than comparing a
+// single scalar - so a "read on code, admin on packages" team and a "admin
+// on code, read on packages" team combine into admin on both.
 func (u *User) AddRepo(r Repo) {
-	if existing, ok := u.Repos[r.Name]; ok {
-		// our existing repo has greater permissions; quit
-		if existing.Level > r.Level {
-			return
-		}
+	existing, ok := u.Repos[r.Name]
+	if !ok {
+		u.Repos[r.Name] = r
+		return
 	}
-	u.Repos[r.Name] = r
-}
-
-type RepoWrapper struct {
-	Repos []Repo `json:"repositories"`
-}
 
-type TeamWrapper struct {
-	Teams []Team `json:"teams"`
-}
-
-type TeamRepoWrapper struct {
-	Repos []TeamRepo `json:"repositoryAccessList"`
+	merged := existing
+	merged.Units = map[Unit]Access{}
+	for _, unit := range units {
+		merged.Units[unit] = maxAccess(existing.unitLevel(unit), r.unitLevel(unit))
+	}
+	if r.Level > merged.Level {
+		merged.Level = r.Level
+	}
+	u.Repos[r.Name] = merged
 }
 
-type TeamMemberWrapper struct {
-	Members []TeamMember `json:"members"`
+// maxAccess returns the greater of two access levels.
+func maxAccess(a, b Access) Access {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 type TeamMember struct {